@@ -0,0 +1,91 @@
+// Package aferofs adapts a boltfs.Volume to the afero.Fs interface, so
+// boltfs can be used as a storage backend by any project already built
+// on top of afero (Hugo, Viper, ...).
+package aferofs
+
+import (
+	"os"
+	"time"
+
+	"github.com/mcuadros/boltfs"
+	"github.com/spf13/afero"
+)
+
+// Fs adapts a *boltfs.Volume to afero.Fs.
+type Fs struct {
+	v *boltfs.Volume
+}
+
+// NewFs returns an afero.Fs backed by v.
+func NewFs(v *boltfs.Volume) afero.Fs {
+	return &Fs{v: v}
+}
+
+// Name returns the name of this FileSystem.
+func (fs *Fs) Name() string {
+	return "boltfs"
+}
+
+// Create creates a file in the filesystem, returning the file and an
+// error, if any happens.
+func (fs *Fs) Create(name string) (afero.File, error) {
+	return fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// Mkdir creates a directory in the filesystem, returning an error if
+// any happens.
+func (fs *Fs) Mkdir(name string, perm os.FileMode) error {
+	return fs.v.Mkdir(name, perm)
+}
+
+// MkdirAll creates a directory path and all parents that do not exist
+// yet.
+func (fs *Fs) MkdirAll(path string, perm os.FileMode) error {
+	return fs.v.MkdirAll(path, perm)
+}
+
+// Open opens a file, returning it or an error, if any happens.
+func (fs *Fs) Open(name string) (afero.File, error) {
+	return fs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens a file using the given flags and the given mode.
+func (fs *Fs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	return fs.v.OpenFile(name, flag, perm)
+}
+
+// Remove removes a file identified by name, returning an error, if any
+// happens.
+func (fs *Fs) Remove(name string) error {
+	return fs.v.Remove(name)
+}
+
+// RemoveAll removes a directory path and any children it contains.
+func (fs *Fs) RemoveAll(path string) error {
+	return fs.v.RemoveAll(path)
+}
+
+// Rename renames a file.
+func (fs *Fs) Rename(oldname, newname string) error {
+	return fs.v.Rename(oldname, newname)
+}
+
+// Stat returns a FileInfo describing the named file.
+func (fs *Fs) Stat(name string) (os.FileInfo, error) {
+	return fs.v.Stat(name)
+}
+
+// Chmod changes the mode of the named file.
+func (fs *Fs) Chmod(name string, mode os.FileMode) error {
+	return fs.v.Chmod(name, mode)
+}
+
+// Chown changes the uid and gid of the named file.
+func (fs *Fs) Chown(name string, uid, gid int) error {
+	return fs.v.Chown(name, uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (fs *Fs) Chtimes(name string, atime, mtime time.Time) error {
+	return fs.v.Chtimes(name, atime, mtime)
+}
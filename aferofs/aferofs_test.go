@@ -0,0 +1,139 @@
+package aferofs
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mcuadros/boltfs"
+	"github.com/spf13/afero"
+)
+
+func newTestFs(t *testing.T) afero.Fs {
+	dir, err := ioutil.TempDir("", "boltfs-afero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	v, err := boltfs.NewVolume(filepath.Join(dir, "afero.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { v.Close() })
+
+	return NewFs(v)
+}
+
+func TestFsCreateAndOpen(t *testing.T) {
+	fs := newTestFs(t)
+
+	f, err := fs.Create("/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteString("hi"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = fs.Open("/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "hi" {
+		t.Fatalf("got %q, want %q", got, "hi")
+	}
+}
+
+func TestFsMkdirAllAndRemoveAll(t *testing.T) {
+	fs := newTestFs(t)
+
+	if err := fs.MkdirAll("/a/b/c", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.Stat("/a/b/c"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.RemoveAll("/a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.Stat("/a"); err == nil {
+		t.Fatal("expected /a to be gone")
+	}
+}
+
+func TestFsRename(t *testing.T) {
+	fs := newTestFs(t)
+
+	f, err := fs.Create("/old.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteString("content"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Rename("/old.txt", "/new.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.Stat("/old.txt"); err == nil {
+		t.Fatal("expected /old.txt to be gone")
+	}
+
+	f, err = fs.Open("/new.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "content" {
+		t.Fatalf("got %q, want %q", got, "content")
+	}
+}
+
+func TestFsRemoveNonEmptyDirFails(t *testing.T) {
+	fs := newTestFs(t)
+
+	if err := fs.MkdirAll("/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.Create("/dir/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Remove("/dir"); err == nil {
+		t.Fatal("expected Remove of a non-empty directory to fail")
+	}
+}
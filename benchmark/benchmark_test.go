@@ -22,6 +22,7 @@ var _ = Suite(&FSSuite{})
 
 const FixtureTarPattern = "fixtures/%d_files.tar"
 const FixtureDbParttern = "fixtures/%d_files.db"
+const FixtureCompressedDbPattern = "fixtures/%d_files.gz.db"
 
 const RandomSeed = 42
 
@@ -33,6 +34,10 @@ func (s *FSSuite) SetUpSuite(c *C) {
 	files78 = buildVolumeFromTar(78)
 	files6133 = buildVolumeFromTar(6133)
 	files820 = buildVolumeFromTar(820)
+
+	buildCompressedVolumeFromTar(78)
+	buildCompressedVolumeFromTar(820)
+	buildCompressedVolumeFromTar(6133)
 }
 
 func (s *FSSuite) BenchmarkReadingRandomFilesFromTar_78(c *C) {
@@ -80,41 +85,104 @@ func (s *FSSuite) BenchmarkReadingRandomFilesFromDb_6k(c *C) {
 	}
 }
 
+func (s *FSSuite) BenchmarkReadingRandomFilesFromCompressedDb_78(c *C) {
+	rand.Seed(42)
+	for i := 0; i < c.N; i++ {
+		openCompressedDbAndReadFile(78, files78)
+	}
+}
+
+func (s *FSSuite) BenchmarkReadingRandomFilesFromCompressedDb_1k(c *C) {
+	rand.Seed(42)
+	for i := 0; i < c.N; i++ {
+		openCompressedDbAndReadFile(820, files820)
+	}
+}
+
+func (s *FSSuite) BenchmarkReadingRandomFilesFromCompressedDb_6k(c *C) {
+	rand.Seed(42)
+	for i := 0; i < c.N; i++ {
+		openCompressedDbAndReadFile(6133, files6133)
+	}
+}
+
 func buildVolumeFromTar(files int) map[int]string {
-	result := make(map[int]string, files)
+	result := tarNames(fmt.Sprintf(FixtureTarPattern, files))
 
 	file, err := os.Open(fmt.Sprintf(FixtureTarPattern, files))
-	if err != nil {
-		panic(err)
-	}
+	ifErrPanic(err)
+	defer file.Close()
 
 	v, err := boltfs.NewVolume(fmt.Sprintf(FixtureDbParttern, files))
-	if err != nil {
-		panic(err)
-	}
+	ifErrPanic(err)
 
-	tar := tar.NewReader(file)
+	_, err = v.ImportTar(file)
+	ifErrPanic(err)
+
+	v.Close()
+	return result
+}
+
+func buildCompressedVolumeFromTar(files int) map[int]string {
+	result := tarNames(fmt.Sprintf(FixtureTarPattern, files))
+
+	file, err := os.Open(fmt.Sprintf(FixtureTarPattern, files))
+	ifErrPanic(err)
+	defer file.Close()
+
+	v, err := boltfs.NewVolumeWithOptions(
+		fmt.Sprintf(FixtureCompressedDbPattern, files),
+		boltfs.WithCodec(boltfs.Gzip),
+	)
+	ifErrPanic(err)
+
+	_, err = v.ImportTar(file)
+	ifErrPanic(err)
+
+	v.Close()
+	return result
+}
+
+// tarNames returns the name of every entry in the tar archive at path,
+// indexed in archive order, so benchmarks can pick a random fixture file
+// by number without re-reading the archive themselves.
+func tarNames(path string) map[int]string {
+	file, err := os.Open(path)
+	ifErrPanic(err)
+	defer file.Close()
+
+	result := make(map[int]string)
+	tr := tar.NewReader(file)
 	cur := 0
 	for {
-		hdr, err := tar.Next()
+		hdr, err := tr.Next()
 		if err == io.EOF {
-			break
+			return result
 		}
 		ifErrPanic(err)
 
-		file, err := v.Open(hdr.Name)
-		ifErrPanic(err)
-
-		_, err = io.Copy(file, tar)
-		ifErrPanic(err)
-		file.Close()
-
 		result[cur] = hdr.Name
 		cur++
 	}
+}
+
+func openCompressedDbAndReadFile(files int, names map[int]string) {
+	randomFile := names[rand.Intn(files)]
+
+	v, err := boltfs.NewVolumeWithOptions(
+		fmt.Sprintf(FixtureCompressedDbPattern, files),
+		boltfs.WithCodec(boltfs.Gzip),
+	)
+	ifErrPanic(err)
+
+	file, err := v.Open(randomFile)
+	ifErrPanic(err)
+
+	buf := bytes.NewBuffer(nil)
+	_, err = io.Copy(buf, file)
+	ifErrPanic(err)
 
 	v.Close()
-	return result
 }
 
 func openDbAndReadFile(files int, names map[int]string) {
@@ -0,0 +1,43 @@
+// Command boltfs-mount mounts an existing boltfs Volume as a real
+// filesystem using FUSE.
+//
+//	boltfs-mount [-rw] volume.db mountpoint
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/mcuadros/boltfs"
+	"github.com/mcuadros/boltfs/fuse"
+)
+
+func main() {
+	rw := flag.Bool("rw", false, "allow writes through the mount")
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		log.Fatalf("usage: %s [-rw] volume.db mountpoint", os.Args[0])
+	}
+
+	dbPath, mountpoint := flag.Arg(0), flag.Arg(1)
+
+	v, err := boltfs.NewVolume(dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer v.Close()
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		<-sig
+		os.Exit(0)
+	}()
+
+	if err := fuse.Serve(v, mountpoint, *rw); err != nil {
+		log.Fatal(err)
+	}
+}
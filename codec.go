@@ -0,0 +1,156 @@
+package boltfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// codecHeaderKey is the tar.Header PAX record a file's codec name is
+// stored under, so mixed volumes (some files compressed, some not, or
+// compressed with different codecs) remain readable.
+const codecHeaderKey = "boltfs.codec"
+
+// Codec transparently compresses and decompresses the blocks of a
+// file's contents.
+type Codec interface {
+	// Encode wraps w, compressing everything written to the result
+	// before it reaches w.
+	Encode(w io.Writer) io.WriteCloser
+
+	// Decode wraps r, decompressing everything read from it.
+	Decode(r io.Reader) (io.ReadCloser, error)
+
+	// Name identifies the codec; it is the value stored in a file's
+	// header so it can be decoded again regardless of the Volume's
+	// current default.
+	Name() string
+}
+
+// None stores blocks as-is.
+var None Codec = noneCodec{}
+
+// Gzip compresses blocks with compress/gzip.
+var Gzip Codec = gzipCodec{}
+
+// Snappy compresses blocks with github.com/golang/snappy.
+var Snappy Codec = snappyCodec{}
+
+// Zstd compresses blocks with github.com/klauspost/compress/zstd.
+var Zstd Codec = zstdCodec{}
+
+var codecsByName = map[string]Codec{
+	None.Name():   None,
+	Gzip.Name():   Gzip,
+	Snappy.Name(): Snappy,
+	Zstd.Name():   Zstd,
+}
+
+type noneCodec struct{}
+
+func (noneCodec) Name() string { return "none" }
+
+func (noneCodec) Encode(w io.Writer) io.WriteCloser {
+	return nopWriteCloser{w}
+}
+
+func (noneCodec) Decode(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(r), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string { return "gzip" }
+
+func (gzipCodec) Encode(w io.Writer) io.WriteCloser {
+	return gzip.NewWriter(w)
+}
+
+func (gzipCodec) Decode(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type snappyCodec struct{}
+
+func (snappyCodec) Name() string { return "snappy" }
+
+func (snappyCodec) Encode(w io.Writer) io.WriteCloser {
+	return snappy.NewBufferedWriter(w)
+}
+
+func (snappyCodec) Decode(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(snappy.NewReader(r)), nil
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) Name() string { return "zstd" }
+
+func (zstdCodec) Encode(w io.Writer) io.WriteCloser {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		// Only returned for invalid options, none of which are used
+		// here, so this can't actually happen.
+		panic(err)
+	}
+
+	return zw
+}
+
+func (zstdCodec) Decode(r io.Reader) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return zr.IOReadCloser(), nil
+}
+
+// encodeBlock compresses block with codec, returning the bytes to
+// store.
+func encodeBlock(codec Codec, block []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	w := codec.Encode(buf)
+
+	if _, err := w.Write(block); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeBlock decompresses raw, which was stored with the codec
+// registered under name, falling back to None for unknown or empty
+// names so files written before codecs existed keep working.
+func decodeBlock(name string, raw []byte) ([]byte, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	codec, ok := codecsByName[name]
+	if !ok {
+		codec = None
+	}
+
+	r, err := codec.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
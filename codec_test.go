@@ -0,0 +1,148 @@
+package boltfs
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCodecsRoundTrip(t *testing.T) {
+	codecs := []Codec{None, Gzip, Snappy, Zstd}
+
+	for _, codec := range codecs {
+		t.Run(codec.Name(), func(t *testing.T) {
+			encoded, err := encodeBlock(codec, []byte("the quick brown fox"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := decodeBlock(codec.Name(), encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != "the quick brown fox" {
+				t.Fatalf("got %q", got)
+			}
+		})
+	}
+}
+
+func TestVolumeMixedCodecs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltfs-codec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolumeWithOptions(filepath.Join(dir, "mixed.db"), WithCodec(Gzip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	f, err := v.Open("/gzipped.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("compressed with gzip"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	v.Codec = None
+
+	f, err = v.Open("/plain.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("stored uncompressed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"/gzipped.txt": "compressed with gzip",
+		"/plain.txt":   "stored uncompressed",
+	} {
+		f, err := v.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != want {
+			t.Fatalf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestVolumeCodecChangeOnExistingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltfs-codec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolumeWithOptions(filepath.Join(dir, "recode.db"), WithCodec(Gzip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	v.BlockSize = 4
+
+	f, err := v.Open("/mixed.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("0123456789"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen under a different codec and append: the blocks already on
+	// disk were gzip-encoded, but the header is about to be rewritten
+	// to "none", so those blocks must be re-encoded along with it.
+	v.Codec = None
+
+	f, err = v.Open("/mixed.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("abcdefghij"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = v.Open("/mixed.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "0123456789abcdefghij"; string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
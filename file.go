@@ -2,26 +2,38 @@ package boltfs
 
 import (
 	"archive/tar"
-	"bytes"
 	"errors"
-	"fmt"
 	"io"
 	"os"
+	"path"
 )
 
 var (
-	NotDirectoryErr = errors.New("not a directory")
-	ClosedFileErr   = errors.New("cannot read/write on a closed file")
-	NonReadableErr  = errors.New("cannot read from a O_WRONLY file")
-	NonWritableErr  = errors.New("cannot write from on a not O_WRONLY or O_RDWR file")
+	NotDirectoryErr   = errors.New("not a directory")
+	ClosedFileErr     = errors.New("cannot read/write on a closed file")
+	NonReadableErr    = errors.New("cannot read from a O_WRONLY file")
+	NonWritableErr    = errors.New("cannot write from on a not O_WRONLY or O_RDWR file")
+	InvalidWhenceErr  = errors.New("invalid whence")
+	NegativeOffsetErr = errors.New("negative offset")
 )
 
+// File represents an open file in a Volume. Its contents are read and
+// written in fixed-size blocks: ReadAt and WriteAt fault in only the
+// blocks a call actually touches, and Read/Write/Seek share
+// the same mechanism through a cursor.
 type File struct {
 	flag int
 	hdr  tar.Header
-	buf  *bytes.Buffer
 	v    *Volume
 
+	off   int64
+	cache map[int64][]byte
+	dirty map[int64]bool
+
+	// truncateFrom is the index of the first block to delete on the
+	// next Sync, or -1 if Truncate hasn't shrunk the file since then.
+	truncateFrom int64
+
 	isClosed   bool
 	isWritable bool
 	isReadable bool
@@ -35,8 +47,11 @@ func newFile(v *Volume, name string, flag int, mode os.FileMode) *File {
 			Name: name,
 			Mode: int64(mode.Perm()),
 		},
-		buf: bytes.NewBuffer(nil),
-		v:   v,
+		v: v,
+
+		cache:        map[int64][]byte{},
+		dirty:        map[int64]bool{},
+		truncateFrom: -1,
 
 		isReadable: isReadable(flag),
 		isWritable: isWritable(flag),
@@ -70,10 +85,18 @@ func (f *File) Chown(uid, gid int) error {
 	return nil
 }
 
-// Close closes the File, rendering it unusable for I/O.
+// Close closes the File, rendering it unusable for I/O. If the File was
+// opened for writing, its dirty blocks and header are flushed first, as
+// by Sync. A File that isn't writable has nothing to flush, so closing
+// it never touches the Volume.
 // It returns an error, if any.
 func (f *File) Close() error {
 	f.isClosed = true
+
+	if !f.isWritable {
+		return nil
+	}
+
 	return f.Sync()
 }
 
@@ -84,7 +107,7 @@ func (f *File) Name() string {
 	return f.hdr.Name
 }
 
-// Read reads up to len(b) bytes from the File.
+// Read reads up to len(b) bytes from the File, advancing the cursor.
 func (f *File) Read(b []byte) (int, error) {
 	if f.isClosed {
 		return 0, &os.PathError{"read", f.hdr.Name, ClosedFileErr}
@@ -94,18 +117,144 @@ func (f *File) Read(b []byte) (int, error) {
 		return 0, &os.PathError{"read", f.hdr.Name, NonReadableErr}
 	}
 
-	n, err := f.buf.Read(b)
+	n, err := f.readAt(b, f.off)
+	f.off += int64(n)
+
+	return n, err
+}
+
+// ReadAt reads len(b) bytes from the File starting at byte offset off.
+// It does not affect, and is not affected by, the cursor used by Read
+// and Write.
+func (f *File) ReadAt(b []byte, off int64) (int, error) {
+	if f.isClosed {
+		return 0, &os.PathError{"read", f.hdr.Name, ClosedFileErr}
+	}
+
+	if !f.isReadable {
+		return 0, &os.PathError{"read", f.hdr.Name, NonReadableErr}
+	}
+
+	return f.readAt(b, off)
+}
+
+func (f *File) readAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &os.PathError{"read", f.hdr.Name, NegativeOffsetErr}
+	}
+
+	total := 0
+	for total < len(b) && off < f.hdr.Size {
+		idx := off / int64(f.v.BlockSize)
+		blockOff := int(off % int64(f.v.BlockSize))
+
+		block, err := f.block(idx)
+		if err != nil {
+			return total, &os.PathError{"read", f.hdr.Name, err}
+		}
+
+		// n is how many bytes this iteration delivers: bounded by the
+		// caller's buffer and by the file's logical size, but not by
+		// the stored block's length. A block shorter than BlockSize,
+		// including one that was never written at all (a sparse hole
+		// faulted in as nil), represents bytes that read back as
+		// zero, not end-of-file.
+		n := f.v.BlockSize - blockOff
+		if want := len(b) - total; want < n {
+			n = want
+		}
+		if avail := f.hdr.Size - off; avail < int64(n) {
+			n = int(avail)
+		}
+
+		have := len(block) - blockOff
+		if have < 0 {
+			have = 0
+		}
+		if have > n {
+			have = n
+		}
+
+		copy(b[total:total+have], block[blockOff:blockOff+have])
+		for i := total + have; i < total+n; i++ {
+			b[i] = 0
+		}
+
+		total += n
+		off += int64(n)
+	}
+
+	if total < len(b) {
+		return total, io.EOF
+	}
+
+	return total, nil
+}
+
+// Readdir reads the contents of the directory associated with the file
+// and returns a slice of up to n FileInfo values, as would be returned
+// by Stat, in directory order. A non-positive n returns every entry.
+func (f *File) Readdir(n int) ([]os.FileInfo, error) {
+	names, err := f.Readdirnames(n)
 	if err != nil {
-		err = &os.PathError{"read", f.hdr.Name, err}
+		return nil, err
 	}
 
-	return n, err
+	infos := make([]os.FileInfo, 0, len(names))
+	for _, name := range names {
+		child, err := f.v.Open(path.Join(f.hdr.Name, name))
+		if err != nil {
+			return nil, err
+		}
+
+		fi, err := child.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, fi)
+	}
+
+	return infos, nil
 }
 
-//func (f *File) ReadAt(b []byte, off int64) (n int, err error)
-//func (f *File) Readdir(n int) (fi []FileInfo, err error)
-//func (f *File) Readdirnames(n int) (names []string, err error)
-//func (f *File) Seek(offset int64, whence int) (ret int64, err error)
+// Readdirnames reads and returns a slice of up to n names of files in
+// the directory, in directory order. A non-positive n returns every
+// name.
+func (f *File) Readdirnames(n int) ([]string, error) {
+	if !f.hdr.FileInfo().IsDir() {
+		return nil, &os.PathError{"readdirnames", f.hdr.Name, NotDirectoryErr}
+	}
+
+	return f.v.readdirnames(f.hdr.Name, n)
+}
+
+// Seek sets the offset for the next Read or Write on the file to
+// offset, interpreted according to whence: SeekStart means relative to
+// the start of the file, SeekCurrent means relative to the current
+// offset, and SeekEnd means relative to the end. It returns the new
+// offset and an error, if any.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = f.off + offset
+	case io.SeekEnd:
+		abs = f.hdr.Size + offset
+	default:
+		return 0, &os.PathError{"seek", f.hdr.Name, InvalidWhenceErr}
+	}
+
+	if abs < 0 {
+		return 0, &os.PathError{"seek", f.hdr.Name, NegativeOffsetErr}
+	}
+
+	f.off = abs
+	return abs, nil
+}
 
 // Stat returns a FileInfo describing the named file.
 func (f *File) Stat() (os.FileInfo, error) {
@@ -117,30 +266,67 @@ func (f *File) Sync() error {
 	return f.v.writeFile(f)
 }
 
-// Truncate changes the size of the file.
+// Truncate changes the size of the file, dropping any block-cached data
+// beyond size. The trailing blocks are actually freed on the next Sync.
 func (f *File) Truncate(size int64) error {
-	f.buf.Truncate(int(size))
+	if size < f.hdr.Size {
+		lastIdx := size / int64(f.v.BlockSize)
+		lastOff := int(size % int64(f.v.BlockSize))
+
+		for idx, block := range f.cache {
+			switch {
+			case idx > lastIdx:
+				delete(f.cache, idx)
+				delete(f.dirty, idx)
+			case idx == lastIdx && lastOff < len(block):
+				f.cache[idx] = block[:lastOff]
+				f.dirty[idx] = true
+			}
+		}
+
+		// The boundary block may hold on-disk data past lastOff even
+		// though it was never faulted into the cache above; fault it in
+		// here so it gets rewritten short rather than left at full
+		// length with a shrunk hdr.Size.
+		if _, cached := f.cache[lastIdx]; !cached {
+			block, err := f.block(lastIdx)
+			if err != nil {
+				return err
+			}
+
+			if lastOff < len(block) {
+				f.cache[lastIdx] = block[:lastOff]
+				f.dirty[lastIdx] = true
+			}
+		}
+
+		if f.truncateFrom < 0 || lastIdx+1 < f.truncateFrom {
+			f.truncateFrom = lastIdx + 1
+		}
+	}
 
+	f.hdr.Size = size
 	return nil
 }
 
-// Write writes len(b) bytes to the File.
+// Write writes len(b) bytes to the File at the cursor, growing the file
+// and faulting in only the blocks it touches.
 // It returns the number of bytes written and an error, if any.
 // Write returns a non-nil error when n != len(b).
 func (f *File) Write(b []byte) (int, error) {
 	if f.isClosed {
-		return 0, &os.PathError{"read", f.hdr.Name, ClosedFileErr}
+		return 0, &os.PathError{"write", f.hdr.Name, ClosedFileErr}
 	}
 
 	if !f.isWritable {
-		return 0, &os.PathError{"read", f.hdr.Name, NonWritableErr}
+		return 0, &os.PathError{"write", f.hdr.Name, NonWritableErr}
 	}
 
-	n, err := f.buf.Write(b)
-	f.hdr.Size += int64(n)
+	n, err := f.writeAt(b, f.off)
+	f.off += int64(n)
 
 	if err != nil {
-		err = &os.PathError{"write", f.hdr.Name, err}
+		return n, err
 	}
 
 	if n != len(b) {
@@ -153,10 +339,104 @@ func (f *File) Write(b []byte) (int, error) {
 		}
 	}
 
-	return n, err
+	return n, nil
+}
+
+// WriteAt writes len(b) bytes to the File starting at byte offset off.
+// It does not affect, and is not affected by, the cursor used by Read
+// and Write.
+func (f *File) WriteAt(b []byte, off int64) (int, error) {
+	if f.isClosed {
+		return 0, &os.PathError{"write", f.hdr.Name, ClosedFileErr}
+	}
+
+	if !f.isWritable {
+		return 0, &os.PathError{"write", f.hdr.Name, NonWritableErr}
+	}
+
+	n, err := f.writeAt(b, off)
+	if err != nil {
+		return n, err
+	}
+
+	if f.isSync {
+		if err := f.Sync(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
 }
 
-//func (f *File) WriteAt(b []byte, off int64) (n int, err error) {}
+func (f *File) writeAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &os.PathError{"write", f.hdr.Name, NegativeOffsetErr}
+	}
+
+	total := 0
+	for total < len(b) {
+		idx := off / int64(f.v.BlockSize)
+		blockOff := int(off % int64(f.v.BlockSize))
+
+		block, err := f.block(idx)
+		if err != nil {
+			return total, &os.PathError{"write", f.hdr.Name, err}
+		}
+
+		room := f.v.BlockSize - blockOff
+		n := len(b) - total
+		if n > room {
+			n = room
+		}
+
+		if blockOff+n > len(block) {
+			grown := make([]byte, blockOff+n)
+			copy(grown, block)
+			block = grown
+		}
+
+		copy(block[blockOff:blockOff+n], b[total:total+n])
+
+		f.cache[idx] = block
+		f.dirty[idx] = true
+
+		total += n
+		off += int64(n)
+	}
+
+	if off > f.hdr.Size {
+		f.hdr.Size = off
+	}
+
+	return total, nil
+}
+
+// block returns, faulting it in from the Volume if necessary, the
+// cached contents of the idx'th block.
+func (f *File) block(idx int64) ([]byte, error) {
+	if block, ok := f.cache[idx]; ok {
+		return block, nil
+	}
+
+	// Blocks a Truncate dropped stay on disk until the next Sync, but
+	// read back as holes.
+	if f.truncateFrom >= 0 && idx >= f.truncateFrom {
+		return nil, nil
+	}
+
+	raw, err := f.v.readBlock(f.hdr.Name, idx)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := decodeBlock(f.hdr.PAXRecords[codecHeaderKey], raw)
+	if err != nil {
+		return nil, err
+	}
+
+	f.cache[idx] = block
+	return block, nil
+}
 
 // WriteString is like Write, but writes the contents of string s rather than
 // a slice of bytes.
@@ -0,0 +1,220 @@
+package boltfs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestVolume(t *testing.T) *Volume {
+	dir, err := ioutil.TempDir("", "boltfs-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	v, err := NewVolume(filepath.Join(dir, "file.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { v.Close() })
+
+	v.BlockSize = 4
+
+	return v
+}
+
+func TestFileSeekReadAtWriteAt(t *testing.T) {
+	v := newTestVolume(t)
+
+	f, err := v.Open("/blocks.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteString("hello world"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteAt([]byte("WORLD"), 6); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]byte, 11)
+	if _, err := io.ReadFull(f, got); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "hello WORLD"; string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	at := make([]byte, 5)
+	if _, err := f.ReadAt(at, 6); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "WORLD"; string(at) != want {
+		t.Fatalf("got %q, want %q", at, want)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileTruncateFreesBlocks(t *testing.T) {
+	v := newTestVolume(t)
+
+	f, err := v.Open("/trunc.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteString("0123456789"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Truncate(3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for idx := int64(1); idx <= 2; idx++ {
+		if raw, err := v.readBlock("/trunc.txt", idx); err != nil || raw != nil {
+			t.Fatalf("block %d still stored after Truncate: %q, err %v", idx, raw, err)
+		}
+	}
+
+	f, err = v.Open("/trunc.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "012"; !bytes.Equal(got, []byte(want)) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestFileTruncateAcrossSessions(t *testing.T) {
+	v := newTestVolume(t)
+
+	f, err := v.Open("/trunc.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteString("0123456789012345"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = v.Open("/trunc.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Truncate(6); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = v.Open("/trunc.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "012345"; !bytes.Equal(got, []byte(want)) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestFileCloseReadOnlyDoesNotSync(t *testing.T) {
+	v := newTestVolume(t)
+
+	f, err := v.Open("/readonly.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteString("hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = v.OpenFile("/readonly.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.ReadAll(f); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sync (and so any write-path Close would take) hits the db, so
+	// closing the Volume first proves a read-only Close never tries:
+	// if it did, f.Close below would fail against the closed db.
+	if err := v.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("closing a read-only File must not sync, got %s", err)
+	}
+}
+
+func TestFileReadSparseHole(t *testing.T) {
+	v := newTestVolume(t)
+
+	f, err := v.Open("/sparse.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteAt([]byte("Z"), 12); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append(make([]byte, 12), 'Z')
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,193 @@
+package boltfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+)
+
+// FS returns an fs.FS view of the Volume, rooted at its root directory
+// "/" regardless of the working directory set with Chdir, suitable for
+// handing to html/template, http.FS,
+// fs.WalkDir, fs.Glob and similar stdlib consumers. The returned value
+// also implements fs.ReadDirFS, fs.StatFS, fs.GlobFS and fs.ReadFileFS.
+func (v *Volume) FS() fs.FS {
+	return &volumeFS{v}
+}
+
+type volumeFS struct {
+	v *Volume
+}
+
+func (vfs *volumeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f, err := vfs.v.OpenFile(vfs.abs(name), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fsFile{f}, nil
+}
+
+func (vfs *volumeFS) abs(name string) string {
+	if name == "." {
+		return "/"
+	}
+
+	return "/" + name
+}
+
+func (vfs *volumeFS) Stat(name string) (fs.FileInfo, error) {
+	f, err := vfs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Stat()
+}
+
+func (vfs *volumeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	dir, err := vfs.v.OpenFile(vfs.abs(name), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, n := range names {
+		child, err := vfs.v.OpenFile(path.Join(dir.hdr.Name, n), os.O_RDONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		fi, err := child.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &dirEntry{fi})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (vfs *volumeFS) ReadFile(name string) ([]byte, error) {
+	f, err := vfs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// Glob implements fs.GlobFS by walking the whole tree under the root
+// and matching every visited path against pattern with path.Match.
+func (vfs *volumeFS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	err := fs.WalkDir(vfs, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if name == "." {
+			return nil
+		}
+
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			matches = append(matches, name)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// fsFile adapts a *File to fs.File and fs.ReadDirFile.
+type fsFile struct {
+	f *File
+
+	// dirEntries and dirRead implement the fs.ReadDirFile cursor:
+	// the directory is listed once, on the first ReadDir call, and
+	// each call thereafter serves (and drops) a prefix of what's left.
+	dirEntries []fs.DirEntry
+	dirRead    bool
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.f.Stat() }
+func (f *fsFile) Read(b []byte) (int, error) { return f.f.Read(b) }
+func (f *fsFile) Close() error               { return f.f.Close() }
+
+func (f *fsFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.dirRead {
+		infos, err := f.f.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+
+		f.dirEntries = make([]fs.DirEntry, len(infos))
+		for i, fi := range infos {
+			f.dirEntries[i] = &dirEntry{fi}
+		}
+
+		f.dirRead = true
+	}
+
+	if n <= 0 {
+		entries := f.dirEntries
+		f.dirEntries = nil
+		return entries, nil
+	}
+
+	if len(f.dirEntries) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(f.dirEntries) {
+		n = len(f.dirEntries)
+	}
+
+	entries := f.dirEntries[:n]
+	f.dirEntries = f.dirEntries[n:]
+
+	return entries, nil
+}
+
+// dirEntry implements fs.DirEntry backed by the os.FileInfo derived
+// from a stored tar.Header.
+type dirEntry struct {
+	info fs.FileInfo
+}
+
+func (d *dirEntry) Name() string               { return d.info.Name() }
+func (d *dirEntry) IsDir() bool                { return d.info.IsDir() }
+func (d *dirEntry) Type() fs.FileMode          { return d.info.Mode().Type() }
+func (d *dirEntry) Info() (fs.FileInfo, error) { return d.info, nil }
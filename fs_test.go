@@ -0,0 +1,80 @@
+package boltfs
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVolumeFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltfs-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolume(filepath.Join(dir, "fs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	files := map[string]string{
+		"/a.txt":         "hello",
+		"/sub/b.txt":     "world",
+		"/sub/dir/c.txt": "nested",
+	}
+
+	for name, content := range files {
+		f, err := v.Open(name)
+		if err != nil {
+			t.Fatalf("open %s: %s", name, err)
+		}
+
+		if _, err := f.WriteString(content); err != nil {
+			t.Fatalf("write %s: %s", name, err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatalf("close %s: %s", name, err)
+		}
+	}
+
+	expected := make([]string, 0, len(files))
+	for name := range files {
+		expected = append(expected, name[1:])
+	}
+
+	if err := fstest.TestFS(v.FS(), expected...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVolumeFSReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltfs-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolume(filepath.Join(dir, "fs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	if _, err := v.FS().Open("missing.txt"); !os.IsNotExist(err) {
+		t.Fatalf("got err %v, want fs.ErrNotExist", err)
+	}
+
+	if _, err := fs.Stat(v.FS(), "missing.txt"); !os.IsNotExist(err) {
+		t.Fatalf("got err %v, want fs.ErrNotExist", err)
+	}
+
+	if entries, err := v.readdirnames("/", -1); err != nil || len(entries) != 0 {
+		t.Fatalf("FS lookups of a missing path must not create it, got entries %v, err %v", entries, err)
+	}
+}
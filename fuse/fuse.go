@@ -0,0 +1,220 @@
+// Package fuse exposes a boltfs.Volume as a real filesystem through
+// bazil.org/fuse. Mount with Serve; pass Writable to additionally allow
+// Create, Mkdir, Write and Remove through the mount point.
+package fuse
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"bazil.org/fuse"
+	fusefs "bazil.org/fuse/fs"
+
+	"github.com/mcuadros/boltfs"
+)
+
+// FS adapts a *boltfs.Volume to bazil.org/fuse/fs.FS.
+type FS struct {
+	v        *boltfs.Volume
+	Writable bool
+}
+
+// New returns a FS backed by v, mountable with Serve.
+func New(v *boltfs.Volume) *FS {
+	return &FS{v: v}
+}
+
+// Serve mounts the Volume at dir and blocks, serving requests, until
+// the filesystem is unmounted or c is closed.
+func Serve(v *boltfs.Volume, dir string, writable bool) error {
+	c, err := fuse.Mount(dir)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	f := New(v)
+	f.Writable = writable
+
+	return fusefs.Serve(c, f)
+}
+
+// Root returns the root directory of the mounted Volume.
+func (f *FS) Root() (fusefs.Node, error) {
+	return &Dir{fs: f, path: "/"}, nil
+}
+
+// Dir represents a directory inside the mounted Volume.
+type Dir struct {
+	fs   *FS
+	path string
+}
+
+// Attr fills a with the directory's inode and mode.
+func (d *Dir) Attr(ctx context.Context, a *fuse.Attr) error {
+	return attr(d.fs.v, d.path, a)
+}
+
+// Lookup resolves name inside the directory.
+func (d *Dir) Lookup(ctx context.Context, name string) (fusefs.Node, error) {
+	return lookup(d.fs, path.Join(d.path, name))
+}
+
+// ReadDirAll lists the directory's children.
+func (d *Dir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	f, err := d.fs.v.OpenFile(d.path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	dirents := make([]fuse.Dirent, 0, len(names))
+	for _, name := range names {
+		child := path.Join(d.path, name)
+
+		ino, err := d.fs.v.Inode(child)
+		if err != nil {
+			return nil, err
+		}
+
+		fi, err := d.fs.v.Stat(child)
+		if err != nil {
+			return nil, err
+		}
+
+		typ := fuse.DT_File
+		if fi.IsDir() {
+			typ = fuse.DT_Dir
+		}
+
+		dirents = append(dirents, fuse.Dirent{Inode: ino, Name: name, Type: typ})
+	}
+
+	return dirents, nil
+}
+
+// Mkdir creates a child directory. It fails with EPERM unless the FS
+// was opened with Writable set.
+func (d *Dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fusefs.Node, error) {
+	if !d.fs.Writable {
+		return nil, fuse.EPERM
+	}
+
+	child := path.Join(d.path, req.Name)
+	if err := d.fs.v.Mkdir(child, req.Mode); err != nil {
+		return nil, err
+	}
+
+	return &Dir{fs: d.fs, path: child}, nil
+}
+
+// Create creates a child file. It fails with EPERM unless the FS was
+// opened with Writable set.
+func (d *Dir) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fusefs.Node, fusefs.Handle, error) {
+	if !d.fs.Writable {
+		return nil, nil, fuse.EPERM
+	}
+
+	child := path.Join(d.path, req.Name)
+	file, err := d.fs.v.OpenFile(child, os.O_RDWR|os.O_CREATE|os.O_TRUNC, req.Mode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	f := &File{fs: d.fs, path: child}
+	return f, f, nil
+}
+
+// Remove deletes a child file or empty directory. It fails with EPERM
+// unless the FS was opened with Writable set.
+func (d *Dir) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
+	if !d.fs.Writable {
+		return fuse.EPERM
+	}
+
+	return d.fs.v.Remove(path.Join(d.path, req.Name))
+}
+
+// File represents a file inside the mounted Volume.
+type File struct {
+	fs   *FS
+	path string
+}
+
+// Attr fills a with the file's inode, mode and size.
+func (f *File) Attr(ctx context.Context, a *fuse.Attr) error {
+	return attr(f.fs.v, f.path, a)
+}
+
+// ReadAll returns the whole contents of the file.
+func (f *File) ReadAll(ctx context.Context) ([]byte, error) {
+	file, err := f.fs.v.OpenFile(f.path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ioutil.ReadAll(file)
+}
+
+// Write writes req.Data at req.Offset. It fails with EPERM unless the
+// FS was opened with Writable set.
+func (f *File) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) error {
+	if !f.fs.Writable {
+		return fuse.EPERM
+	}
+
+	file, err := f.fs.v.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	n, err := file.WriteAt(req.Data, req.Offset)
+	resp.Size = n
+
+	return err
+}
+
+func lookup(f *FS, p string) (fusefs.Node, error) {
+	fi, err := f.v.Stat(p)
+	if err != nil {
+		return nil, fuse.ENOENT
+	}
+
+	if fi.IsDir() {
+		return &Dir{fs: f, path: p}, nil
+	}
+
+	return &File{fs: f, path: p}, nil
+}
+
+func attr(v *boltfs.Volume, path string, a *fuse.Attr) error {
+	ino, err := v.Inode(path)
+	if err != nil {
+		return err
+	}
+
+	fi, err := v.Stat(path)
+	if err != nil {
+		return fuse.ENOENT
+	}
+
+	a.Inode = ino
+	a.Mode = fi.Mode()
+	a.Size = uint64(fi.Size())
+	a.Mtime = fi.ModTime()
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package fuse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"bazil.org/fuse"
+	fusefs "bazil.org/fuse/fs"
+
+	"github.com/mcuadros/boltfs"
+)
+
+// TestMountReadsFixture mounts a small fixture Volume at a temp dir and
+// reads a file back through the mount point. It is skipped when
+// /dev/fuse isn't available, e.g. in most CI sandboxes.
+func TestMountReadsFixture(t *testing.T) {
+	base, err := ioutil.TempDir("", "boltfs-fuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+
+	v, err := boltfs.NewVolume(filepath.Join(base, "fixture.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := v.Open("/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteString("hello fuse"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mountpoint := filepath.Join(base, "mnt")
+	if err := os.Mkdir(mountpoint, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := fuse.Mount(mountpoint)
+	if err != nil {
+		t.Skipf("fuse unavailable in this environment: %s", err)
+	}
+	defer c.Close()
+	defer fuse.Unmount(mountpoint)
+
+	go fusefs.Serve(c, New(v))
+	<-c.Ready
+	if err := c.MountError; err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(mountpoint, "hello.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "hello fuse" {
+		t.Fatalf("got %q, want %q", data, "hello fuse")
+	}
+}
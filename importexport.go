@@ -0,0 +1,237 @@
+package boltfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ImportTar reads the tar archive in r, creating a file or directory
+// for every entry it contains. It returns the number of entries
+// imported.
+func (v *Volume) ImportTar(r io.Reader) (int, error) {
+	tr := tar.NewReader(r)
+
+	n := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+
+		if err != nil {
+			return n, err
+		}
+
+		if hdr.Typeflag == tar.TypeDir {
+			if err := v.MkdirAll(hdr.Name, os.FileMode(hdr.Mode)); err != nil {
+				return n, err
+			}
+
+			n++
+			continue
+		}
+
+		f, err := v.OpenFile(hdr.Name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(hdr.Mode))
+		if err != nil {
+			return n, err
+		}
+
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return n, err
+		}
+
+		f.hdr.Uid = hdr.Uid
+		f.hdr.Gid = hdr.Gid
+		f.hdr.ModTime = hdr.ModTime
+
+		if err := f.Close(); err != nil {
+			return n, err
+		}
+
+		n++
+	}
+}
+
+// ImportZip reads the zip archive in r, creating a file or directory
+// for every entry it contains. It returns the number of entries
+// imported.
+func (v *Volume) ImportZip(r io.ReaderAt, size int64) (int, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, zf := range zr.File {
+		name := "/" + zf.Name
+
+		if zf.FileInfo().IsDir() {
+			if err := v.MkdirAll(name, zf.Mode()); err != nil {
+				return n, err
+			}
+
+			n++
+			continue
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return n, err
+		}
+
+		f, err := v.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, zf.Mode())
+		if err != nil {
+			rc.Close()
+			return n, err
+		}
+
+		_, err = io.Copy(f, rc)
+		rc.Close()
+		if err != nil {
+			f.Close()
+			return n, err
+		}
+
+		f.hdr.ModTime = zf.Modified
+
+		if err := f.Close(); err != nil {
+			return n, err
+		}
+
+		n++
+	}
+
+	return n, nil
+}
+
+// ExportTar writes every file and directory in the Volume for which
+// filter returns true (or every one, if filter is nil) to w as a tar
+// archive. Since each entry's tar.Header is already stored verbatim,
+// only its contents need to be copied.
+func (v *Volume) ExportTar(w io.Writer, filter func(name string) bool) error {
+	tw := tar.NewWriter(w)
+
+	err := v.walk("/", func(name string, fi os.FileInfo) error {
+		if name == "/" || (filter != nil && !filter(name)) {
+			return nil
+		}
+
+		f, err := v.OpenFile(name, os.O_RDONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		hdr := f.hdr
+		hdr.Name = strings.TrimPrefix(name, "/")
+
+		if len(hdr.PAXRecords) > 0 {
+			records := make(map[string]string, len(hdr.PAXRecords))
+			for k, v := range hdr.PAXRecords {
+				records[k] = v
+			}
+			delete(records, codecHeaderKey)
+			hdr.PAXRecords = records
+		}
+
+		if err := tw.WriteHeader(&hdr); err != nil {
+			return err
+		}
+
+		if hdr.Typeflag == tar.TypeDir {
+			return nil
+		}
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// ExportZip writes every file and directory in the Volume for which
+// filter returns true (or every one, if filter is nil) to w as a zip
+// archive.
+func (v *Volume) ExportZip(w io.Writer, filter func(name string) bool) error {
+	zw := zip.NewWriter(w)
+
+	err := v.walk("/", func(name string, fi os.FileInfo) error {
+		if name == "/" || (filter != nil && !filter(name)) {
+			return nil
+		}
+
+		rel := strings.TrimPrefix(name, "/")
+
+		if fi.IsDir() {
+			_, err := zw.Create(rel + "/")
+			return err
+		}
+
+		fh, err := zip.FileInfoHeader(fi)
+		if err != nil {
+			return err
+		}
+
+		fh.Name = rel
+		fh.Method = zip.Deflate
+
+		entry, err := zw.CreateHeader(fh)
+		if err != nil {
+			return err
+		}
+
+		f, err := v.OpenFile(name, os.O_RDONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(entry, f)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// walk calls fn for name and, if it is a directory, recursively for
+// every one of its children, in directory order.
+func (v *Volume) walk(name string, fn func(name string, fi os.FileInfo) error) error {
+	fi, err := v.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(name, fi); err != nil {
+		return err
+	}
+
+	if !fi.IsDir() {
+		return nil
+	}
+
+	children, err := v.readdirnames(name, -1)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		if err := v.walk(path.Join(name, child), fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package boltfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportExportTar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltfs-tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(src)
+	for _, entry := range []struct {
+		name, body string
+	}{
+		{"a.txt", "hello"},
+		{"sub/b.txt", "world"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{
+			Name: entry.name,
+			Mode: 0644,
+			Size: int64(len(entry.body)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := NewVolume(filepath.Join(dir, "tar.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	n, err := v.ImportTar(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 2 {
+		t.Fatalf("got %d entries, want 2", n)
+	}
+
+	f, err := v.Open("/sub/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "world" {
+		t.Fatalf("got %q, want %q", got, "world")
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := v.ExportTar(out, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]bool{}
+	tr := tar.NewReader(out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := hdr.PAXRecords[codecHeaderKey]; ok {
+			t.Fatalf("exported header for %q leaks internal PAX record %q", hdr.Name, codecHeaderKey)
+		}
+
+		names[hdr.Name] = true
+	}
+
+	for _, want := range []string{"a.txt", "sub", "sub/b.txt"} {
+		if !names[want] {
+			t.Fatalf("exported tar is missing %q (got %v)", want, names)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package boltfs
+
+// Option configures a Volume constructed with NewVolumeWithOptions.
+type Option func(*Volume)
+
+// WithCodec sets the Codec new writes are compressed with. It defaults
+// to None.
+func WithCodec(c Codec) Option {
+	return func(v *Volume) { v.Codec = c }
+}
+
+// WithMinCompressSize sets the minimum total file size, in bytes,
+// below which a file is stored uncompressed regardless of Codec. It
+// defaults to 0, compressing every file.
+func WithMinCompressSize(n int) Option {
+	return func(v *Volume) { v.MinCompressSize = n }
+}
+
+// NewVolumeWithOptions is like NewVolume but applies opts to the
+// resulting Volume before returning it.
+func NewVolumeWithOptions(path string, opts ...Option) (*Volume, error) {
+	v, err := NewVolume(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v, nil
+}
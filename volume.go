@@ -0,0 +1,677 @@
+package boltfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+var (
+	NotExistErr          = errors.New("file does not exist")
+	DirectoryNotEmptyErr = errors.New("directory not empty")
+)
+
+var (
+	metaBucket   = []byte("meta")
+	blocksBucket = []byte("blocks")
+	indexBucket  = []byte("index")
+	inodeBucket  = []byte("inodes")
+)
+
+// DefaultBlockSize is the block size used by a Volume opened with
+// NewVolume. It can be changed per Volume via the BlockSize field
+// before any file belonging to it is written.
+const DefaultBlockSize = 32 * 1024
+
+// Volume represents a filesystem backed by a single bolt database file.
+// A file's tar.Header metadata lives in the meta bucket, keyed by its
+// full path; its contents are split into fixed-size blocks stored under
+// a per-path sub-bucket of blocks, keyed by block index, so that Read,
+// Write, Seek, ReadAt and WriteAt only ever fault in the blocks they
+// touch. A secondary index bucket keeps, for every directory, the list
+// of its direct children so listings don't require scanning the whole
+// database.
+type Volume struct {
+	db  *bolt.DB
+	cwd string
+
+	// BlockSize is the size, in bytes, of the chunks a file's contents
+	// are split into. It defaults to DefaultBlockSize and should not be
+	// changed once files have been written with a different value.
+	BlockSize int
+
+	// Codec compresses the blocks of files written from now on. It
+	// defaults to None; use WithCodec with NewVolumeWithOptions to pick
+	// another one.
+	Codec Codec
+
+	// MinCompressSize is the total file size, in bytes, below which a
+	// file is stored uncompressed regardless of Codec. It defaults to 0.
+	MinCompressSize int
+}
+
+// NewVolume opens the bolt database at path, creating it if it does not
+// already exist, and returns a Volume backed by it.
+func NewVolume(path string) (*Volume, error) {
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{metaBucket, blocksBucket, indexBucket, inodeBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Volume{db: db, cwd: "/", BlockSize: DefaultBlockSize, Codec: None}, nil
+}
+
+// Close releases all resources held by the Volume.
+func (v *Volume) Close() error {
+	return v.db.Close()
+}
+
+// Chdir changes the current working directory used to resolve relative
+// paths passed to Open.
+func (v *Volume) Chdir(name string) error {
+	v.cwd = v.abs(name)
+	return nil
+}
+
+// Open opens the named file for reading and writing, creating it with
+// mode 0644 if it does not yet exist, and returns a *File
+// ready for reading and writing. It is equivalent to
+// OpenFile(name, os.O_RDWR|os.O_CREATE, 0644).
+func (v *Volume) Open(name string) (*File, error) {
+	return v.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+}
+
+// OpenFile opens the named file with the given flag (os.O_RDONLY,
+// os.O_CREATE, os.O_TRUNC, os.O_APPEND, ...) and, if it creates the
+// file, the given perm. If there is an error, it will be of type
+// *os.PathError.
+func (v *Volume) OpenFile(name string, flag int, perm os.FileMode) (*File, error) {
+	name = v.abs(name)
+
+	f := newFile(v, name, flag, perm)
+
+	var exists bool
+	err := v.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(metaBucket).Get([]byte(name))
+		if data != nil {
+			exists = true
+			return gob.NewDecoder(bytes.NewReader(data)).Decode(&f.hdr)
+		}
+
+		// No stored metadata: if the index knows this path as a parent
+		// of other entries, it is an implicit directory.
+		if tx.Bucket(indexBucket).Bucket([]byte(name)) != nil {
+			exists = true
+			f.hdr = tar.Header{
+				Name:     name,
+				Typeflag: tar.TypeDir,
+				Mode:     int64(os.ModePerm),
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, &os.PathError{"open", name, err}
+	}
+
+	if !exists && flag&os.O_CREATE == 0 {
+		return nil, &os.PathError{"open", name, os.ErrNotExist}
+	}
+
+	if exists && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+		return nil, &os.PathError{"open", name, os.ErrExist}
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		f.hdr.Size = 0
+		f.cache = map[int64][]byte{}
+		f.dirty = map[int64]bool{}
+		f.truncateFrom = 0
+	}
+
+	if flag&os.O_APPEND != 0 {
+		f.off = f.hdr.Size
+	}
+
+	return f, nil
+}
+
+// Stat returns a FileInfo describing the named file. Unlike Open, it
+// does not create the file if it does not exist.
+func (v *Volume) Stat(name string) (os.FileInfo, error) {
+	f, err := v.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Stat()
+}
+
+// Mkdir creates a new, empty directory with the given name and
+// permission bits. If there is an error, it will be of type
+// *os.PathError.
+func (v *Volume) Mkdir(name string, perm os.FileMode) error {
+	name = v.abs(name)
+
+	f := newFile(v, name, os.O_RDWR, perm)
+	f.hdr.Typeflag = tar.TypeDir
+
+	if err := v.writeFile(f); err != nil {
+		return &os.PathError{"mkdir", name, err}
+	}
+
+	return nil
+}
+
+// MkdirAll creates a directory named name, along with any necessary
+// parents, and returns nil, or else returns an error.
+func (v *Volume) MkdirAll(name string, perm os.FileMode) error {
+	name = v.abs(name)
+
+	for _, dir := range ancestors(name) {
+		if err := v.Mkdir(dir, perm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ancestors returns name and every one of its parent directories, in
+// root-to-leaf order, excluding the root itself.
+func ancestors(name string) []string {
+	if name == "/" {
+		return nil
+	}
+
+	var dirs []string
+	for p := name; p != "/"; p = path.Dir(p) {
+		dirs = append([]string{p}, dirs...)
+	}
+
+	return dirs
+}
+
+// Remove removes the named file or empty directory.
+// If there is an error, it will be of type *os.PathError.
+func (v *Volume) Remove(name string) error {
+	name = v.abs(name)
+
+	err := v.db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(indexBucket).Bucket([]byte(name)); b != nil {
+			if b.Stats().KeyN > 0 {
+				return DirectoryNotEmptyErr
+			}
+
+			if err := tx.Bucket(indexBucket).DeleteBucket([]byte(name)); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Bucket(metaBucket).Delete([]byte(name)); err != nil {
+			return err
+		}
+
+		if tx.Bucket(blocksBucket).Bucket([]byte(name)) != nil {
+			if err := tx.Bucket(blocksBucket).DeleteBucket([]byte(name)); err != nil {
+				return err
+			}
+		}
+
+		return v.indexDelete(tx, name)
+	})
+
+	if err != nil {
+		return &os.PathError{"remove", name, err}
+	}
+
+	return nil
+}
+
+// RemoveAll removes name and any children it contains. It does not
+// return an error if name does not exist.
+func (v *Volume) RemoveAll(name string) error {
+	name = v.abs(name)
+
+	children, err := v.readdirnames(name, -1)
+	if err != nil {
+		return &os.PathError{"removeall", name, err}
+	}
+
+	for _, child := range children {
+		if err := v.RemoveAll(path.Join(name, child)); err != nil {
+			return err
+		}
+	}
+
+	err = v.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(metaBucket).Delete([]byte(name)); err != nil {
+			return err
+		}
+
+		if tx.Bucket(blocksBucket).Bucket([]byte(name)) != nil {
+			if err := tx.Bucket(blocksBucket).DeleteBucket([]byte(name)); err != nil {
+				return err
+			}
+		}
+
+		if tx.Bucket(indexBucket).Bucket([]byte(name)) != nil {
+			if err := tx.Bucket(indexBucket).DeleteBucket([]byte(name)); err != nil {
+				return err
+			}
+		}
+
+		return v.indexDelete(tx, name)
+	})
+
+	if err != nil {
+		return &os.PathError{"removeall", name, err}
+	}
+
+	return nil
+}
+
+// Rename renames (moves) oldname to newname, along with every one of
+// its children. If there is an error, it will be of type *os.LinkError.
+func (v *Volume) Rename(oldname, newname string) error {
+	oldname, newname = v.abs(oldname), v.abs(newname)
+
+	children, err := v.readdirnames(oldname, -1)
+	if err != nil {
+		return &os.LinkError{"rename", oldname, newname, err}
+	}
+
+	for _, child := range children {
+		if err := v.Rename(path.Join(oldname, child), path.Join(newname, child)); err != nil {
+			return err
+		}
+	}
+
+	err = v.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if data := meta.Get([]byte(oldname)); data != nil {
+			if err := meta.Put([]byte(newname), data); err != nil {
+				return err
+			}
+
+			if err := meta.Delete([]byte(oldname)); err != nil {
+				return err
+			}
+		}
+
+		blocks := tx.Bucket(blocksBucket)
+		if b := blocks.Bucket([]byte(oldname)); b != nil {
+			nb, err := blocks.CreateBucketIfNotExists([]byte(newname))
+			if err != nil {
+				return err
+			}
+
+			if err := b.ForEach(nb.Put); err != nil {
+				return err
+			}
+
+			if err := blocks.DeleteBucket([]byte(oldname)); err != nil {
+				return err
+			}
+		}
+
+		if tx.Bucket(indexBucket).Bucket([]byte(oldname)) != nil {
+			if err := tx.Bucket(indexBucket).DeleteBucket([]byte(oldname)); err != nil {
+				return err
+			}
+		}
+
+		if err := v.indexDelete(tx, oldname); err != nil {
+			return err
+		}
+
+		return v.indexPut(tx, newname)
+	})
+
+	if err != nil {
+		return &os.LinkError{"rename", oldname, newname, err}
+	}
+
+	return nil
+}
+
+// Chmod changes the mode of the named file.
+func (v *Volume) Chmod(name string, mode os.FileMode) error {
+	f, err := v.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+
+	f.hdr.Mode = int64(mode.Perm())
+
+	if err := v.writeFile(f); err != nil {
+		return &os.PathError{"chmod", name, err}
+	}
+
+	return nil
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (v *Volume) Chown(name string, uid, gid int) error {
+	f, err := v.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+
+	f.hdr.Uid = uid
+	f.hdr.Gid = gid
+
+	if err := v.writeFile(f); err != nil {
+		return &os.PathError{"chown", name, err}
+	}
+
+	return nil
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (v *Volume) Chtimes(name string, atime, mtime time.Time) error {
+	f, err := v.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+
+	f.hdr.AccessTime = atime
+	f.hdr.ModTime = mtime
+
+	if err := v.writeFile(f); err != nil {
+		return &os.PathError{"chtimes", name, err}
+	}
+
+	return nil
+}
+
+// indexDelete removes name from its parent's list of children.
+func (v *Volume) indexDelete(tx *bolt.Tx, name string) error {
+	b := tx.Bucket(indexBucket).Bucket([]byte(path.Dir(name)))
+	if b == nil {
+		return nil
+	}
+
+	return b.Delete([]byte(path.Base(name)))
+}
+
+// writeFile persists the header of f, flushes its dirty blocks, removes
+// any blocks a Truncate dropped and updates the directory index so
+// f.hdr.Name shows up in its parent's listing.
+//
+// A file's blocks are all encoded with the same codec, named once in
+// the header's codecHeaderKey PAX record: readers decode every block
+// with that one name, so if v.Codec (or crossing MinCompressSize)
+// changed since the blocks already on disk were written, those blocks
+// are re-encoded here under the new codec, in the same transaction that
+// stores the new name and before the dirty blocks are written, rather
+// than leaving them mismatched with the new name.
+func (v *Volume) writeFile(f *File) error {
+	codec := v.Codec
+	if codec == nil || int(f.hdr.Size) < v.MinCompressSize {
+		codec = None
+	}
+
+	prevCodecName := f.hdr.PAXRecords[codecHeaderKey]
+	recodeAll := prevCodecName != "" && prevCodecName != codec.Name()
+
+	if recodeAll || len(f.dirty) > 0 {
+		if f.hdr.PAXRecords == nil {
+			f.hdr.PAXRecords = map[string]string{}
+		}
+
+		f.hdr.PAXRecords[codecHeaderKey] = codec.Name()
+	}
+
+	hdr := bytes.NewBuffer(nil)
+	if err := gob.NewEncoder(hdr).Encode(&f.hdr); err != nil {
+		return err
+	}
+
+	err := v.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(metaBucket).Put([]byte(f.hdr.Name), hdr.Bytes()); err != nil {
+			return err
+		}
+
+		blocks, err := tx.Bucket(blocksBucket).CreateBucketIfNotExists([]byte(f.hdr.Name))
+		if err != nil {
+			return err
+		}
+
+		if recodeAll {
+			if err := recodeBlocks(blocks, prevCodecName, codec, f.dirty); err != nil {
+				return err
+			}
+		}
+
+		for idx, dirty := range f.dirty {
+			if !dirty {
+				continue
+			}
+
+			encoded, err := encodeBlock(codec, f.cache[idx])
+			if err != nil {
+				return err
+			}
+
+			if err := blocks.Put(blockKey(idx), encoded); err != nil {
+				return err
+			}
+		}
+
+		if f.truncateFrom >= 0 {
+			// Blocks written since the Truncate are dirty and were just
+			// stored above, so they are kept. Keys are collected first
+			// because deleting while the cursor walks the bucket skips
+			// keys.
+			var stale [][]byte
+			c := blocks.Cursor()
+			for k, _ := c.Seek(blockKey(f.truncateFrom)); k != nil; k, _ = c.Next() {
+				if !f.dirty[int64(binary.BigEndian.Uint64(k))] {
+					stale = append(stale, append([]byte(nil), k...))
+				}
+			}
+
+			for _, k := range stale {
+				if err := blocks.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+
+		return v.indexPut(tx, f.hdr.Name)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	f.dirty = map[int64]bool{}
+	f.truncateFrom = -1
+
+	return nil
+}
+
+// recodeBlocks re-encodes every block in blocks from codec from to
+// codec to, skipping indexes marked dirty since those are about to be
+// overwritten anyway, so a file never ends up with some blocks only
+// readable under its old codec name.
+func recodeBlocks(blocks *bolt.Bucket, from string, to Codec, dirty map[int64]bool) error {
+	type entry struct {
+		key, raw []byte
+	}
+
+	var stale []entry
+	c := blocks.Cursor()
+	for k, raw := c.First(); k != nil; k, raw = c.Next() {
+		idx := int64(binary.BigEndian.Uint64(k))
+		if dirty[idx] {
+			continue
+		}
+
+		stale = append(stale, entry{append([]byte(nil), k...), append([]byte(nil), raw...)})
+	}
+
+	for _, e := range stale {
+		decoded, err := decodeBlock(from, e.raw)
+		if err != nil {
+			return err
+		}
+
+		encoded, err := encodeBlock(to, decoded)
+		if err != nil {
+			return err
+		}
+
+		if err := blocks.Put(e.key, encoded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readBlock returns the contents of the idx'th block of name, or nil if
+// that block has never been written.
+func (v *Volume) readBlock(name string, idx int64) ([]byte, error) {
+	var data []byte
+
+	err := v.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(blocksBucket).Bucket([]byte(name))
+		if b == nil {
+			return nil
+		}
+
+		if raw := b.Get(blockKey(idx)); raw != nil {
+			data = append([]byte(nil), raw...)
+		}
+
+		return nil
+	})
+
+	return data, err
+}
+
+func blockKey(idx int64) []byte {
+	return uint64Key(uint64(idx))
+}
+
+func uint64Key(n uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, n)
+	return k
+}
+
+// Inode returns a stable, monotonically assigned inode number for name,
+// allocating one on first use. It is meant for consumers, such as the
+// FUSE adapter, that need identifiers persisting across mounts.
+func (v *Volume) Inode(name string) (uint64, error) {
+	name = v.abs(name)
+
+	var id uint64
+	err := v.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(inodeBucket)
+
+		if raw := b.Get([]byte(name)); raw != nil {
+			id = binary.BigEndian.Uint64(raw)
+			return nil
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		id = seq
+		return b.Put([]byte(name), uint64Key(id))
+	})
+
+	return id, err
+}
+
+// readdirnames returns, in directory order, the names of up to n
+// children of dir as recorded in the index bucket. A non-positive n
+// returns every child.
+func (v *Volume) readdirnames(dir string, n int) ([]string, error) {
+	var names []string
+
+	err := v.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(indexBucket).Bucket([]byte(dir))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, _ []byte) error {
+			names = append(names, string(k))
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	if n > 0 && n < len(names) {
+		names = names[:n]
+	}
+
+	return names, nil
+}
+
+// indexPut registers name, and every one of its ancestor directories,
+// as a child of its immediate parent.
+func (v *Volume) indexPut(tx *bolt.Tx, name string) error {
+	root := tx.Bucket(indexBucket)
+
+	dir, base := path.Dir(name), path.Base(name)
+	for {
+		b, err := root.CreateBucketIfNotExists([]byte(dir))
+		if err != nil {
+			return err
+		}
+
+		if err := b.Put([]byte(base), []byte{}); err != nil {
+			return err
+		}
+
+		if dir == "/" {
+			return nil
+		}
+
+		dir, base = path.Dir(dir), path.Base(dir)
+	}
+}
+
+func (v *Volume) abs(name string) string {
+	if path.IsAbs(name) {
+		return path.Clean(name)
+	}
+
+	return path.Clean(path.Join(v.cwd, name))
+}